@@ -0,0 +1,91 @@
+package breezy
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// decodeBody decodes body according to the Content-Encoding header
+// value. It returns ok=false for encodings we don't know how to
+// handle, in which case the caller should leave the response untouched.
+func decodeBody(encoding string, body []byte) (decoded []byte, ok bool, err error) {
+	switch encoding {
+	case "", "identity":
+		return body, true, nil
+	case "gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, true, fmt.Errorf("breezy: decoding gzip body: %w", err)
+		}
+		defer r.Close()
+		decoded, err = io.ReadAll(r)
+		if err != nil {
+			return nil, true, fmt.Errorf("breezy: decoding gzip body: %w", err)
+		}
+		return decoded, true, nil
+	case "deflate":
+		r := flate.NewReader(bytes.NewReader(body))
+		defer r.Close()
+		decoded, err = io.ReadAll(r)
+		if err != nil {
+			return nil, true, fmt.Errorf("breezy: decoding deflate body: %w", err)
+		}
+		return decoded, true, nil
+	case "br":
+		decoded, err = io.ReadAll(brotli.NewReader(bytes.NewReader(body)))
+		if err != nil {
+			return nil, true, fmt.Errorf("breezy: decoding brotli body: %w", err)
+		}
+		return decoded, true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// encodeBody re-encodes body using the given Content-Encoding value.
+// It is the inverse of decodeBody for the encodings we support.
+func encodeBody(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return nil, fmt.Errorf("breezy: encoding gzip body: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("breezy: encoding gzip body: %w", err)
+		}
+		return buf.Bytes(), nil
+	case "deflate":
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, fmt.Errorf("breezy: encoding deflate body: %w", err)
+		}
+		if _, err := w.Write(body); err != nil {
+			return nil, fmt.Errorf("breezy: encoding deflate body: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("breezy: encoding deflate body: %w", err)
+		}
+		return buf.Bytes(), nil
+	case "br":
+		w := brotli.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return nil, fmt.Errorf("breezy: encoding brotli body: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("breezy: encoding brotli body: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("breezy: unsupported Content-Encoding %q", encoding)
+	}
+}
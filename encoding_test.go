@@ -0,0 +1,144 @@
+package breezy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gzipMiddleware mimics a downstream gziphandler-style middleware that
+// compresses the response body and sets Content-Encoding.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+
+		gw.Write(rec.Body.Bytes())
+		gw.Close()
+
+		for key, values := range rec.Header() {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(rec.Code)
+		w.Write(buf.Bytes())
+	})
+}
+
+func TestMiddlewareInjectsIntoGzippedResponse(t *testing.T) {
+	handler := gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head></head><body>hi</body></html>"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	Middleware(handler).ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", enc)
+	}
+
+	gr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	body, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+
+	if !bytes.Contains(body, []byte("<script>")) {
+		t.Fatalf("expected injected script in decoded body, got: %s", body)
+	}
+	if !bytes.Contains(body, []byte("hi")) {
+		t.Fatalf("expected original content preserved, got: %s", body)
+	}
+}
+
+func TestMiddlewareDoesNotDuplicateHeadersOnGzippedResponse(t *testing.T) {
+	handler := gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("X-Custom", "one")
+		w.Write([]byte("<html><head></head><body>hi</body></html>"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	Middleware(handler).ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	if got := resp.Header["Content-Encoding"]; len(got) != 1 {
+		t.Fatalf("Content-Encoding = %v, want exactly one value", got)
+	}
+	if got := resp.Header["X-Custom"]; len(got) != 1 {
+		t.Fatalf("X-Custom = %v, want exactly one value", got)
+	}
+}
+
+func TestMiddlewareInjectsIntoGzippedResponseWithoutContentType(t *testing.T) {
+	// Deliberately writes straight to w instead of going through
+	// gzipMiddleware's inner httptest.Recorder, whose Write auto-sniffs
+	// and sets a Content-Type we need absent to exercise this case.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		gw.Write([]byte("<html><head></head><body>hi</body></html>"))
+		gw.Close()
+
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	Middleware(handler).ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", enc)
+	}
+
+	gr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	body, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+
+	if !bytes.Contains(body, []byte("<script>")) {
+		t.Fatalf("expected injected script in decoded body despite the missing Content-Type, got: %s", body)
+	}
+}
+
+func TestMiddlewarePassesThroughUnknownEncoding(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write([]byte("<html><head></head></html>"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	Middleware(handler).ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if bytes.Contains(body, []byte("<script>")) {
+		t.Fatalf("expected no injection for unknown encoding, got: %s", body)
+	}
+	if string(body) != "<html><head></head></html>" {
+		t.Fatalf("expected body unchanged, got: %s", body)
+	}
+}
@@ -0,0 +1,76 @@
+package breezy
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeWait bounds how long Hub.run will wait on a single slow or dead
+// client's WriteJSON before giving up on it. Without this, one stalled
+// client (a closed laptop, a throttled tab with a full TCP buffer)
+// would block the broadcast loop indefinitely, and with it every other
+// client and every Broadcast caller.
+const writeWait = 5 * time.Second
+
+// Hub tracks the set of connected live-reload websocket clients and
+// broadcasts messages to all of them.
+type Hub struct {
+	mu     sync.Mutex
+	conns  map[*websocket.Conn]struct{}
+	logger *slog.Logger
+
+	register   chan *websocket.Conn
+	unregister chan *websocket.Conn
+	broadcast  chan any
+}
+
+func newHub(logger *slog.Logger) *Hub {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Hub{
+		conns:      make(map[*websocket.Conn]struct{}),
+		logger:     logger,
+		register:   make(chan *websocket.Conn),
+		unregister: make(chan *websocket.Conn),
+		broadcast:  make(chan any),
+	}
+}
+
+// run processes register/unregister/broadcast requests until stop is
+// closed. It must be run in its own goroutine.
+func (h *Hub) run(stop <-chan struct{}) {
+	for {
+		select {
+		case conn := <-h.register:
+			h.mu.Lock()
+			h.conns[conn] = struct{}{}
+			h.mu.Unlock()
+		case conn := <-h.unregister:
+			h.mu.Lock()
+			delete(h.conns, conn)
+			h.mu.Unlock()
+		case msg := <-h.broadcast:
+			h.mu.Lock()
+			for conn := range h.conns {
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := conn.WriteJSON(msg); err != nil {
+					h.logger.Error("breezy: failed to write to client, disconnecting", "error", err)
+					conn.Close()
+					delete(h.conns, conn)
+				}
+			}
+			h.mu.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Broadcast sends msg, as JSON, to every connected client.
+func (h *Hub) Broadcast(msg any) {
+	h.broadcast <- msg
+}
@@ -0,0 +1,64 @@
+package breezy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestHubDisconnectsClientOnWriteError verifies that Hub.run drops a
+// connection once a write to it fails, instead of leaving it registered
+// and retrying it forever on every subsequent broadcast.
+func TestHubDisconnectsClientOnWriteError(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var serverConn *websocket.Conn
+	ready := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		serverConn = conn
+		close(ready)
+	}))
+	defer ts.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+	clientConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer clientConn.Close()
+	<-ready
+
+	hub := newHub(nil)
+	hub.conns[serverConn] = struct{}{}
+
+	// Close the underlying connection out from under Hub so the next
+	// write fails immediately instead of blocking for writeWait.
+	serverConn.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go hub.run(stop)
+
+	hub.Broadcast(map[string]any{"type": "reload"})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		hub.mu.Lock()
+		_, stillRegistered := hub.conns[serverConn]
+		hub.mu.Unlock()
+		if !stillRegistered {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("expected the closed connection to be removed from Hub.conns")
+}
@@ -2,6 +2,8 @@ package breezy
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -9,36 +11,178 @@ import (
 
 	_ "embed"
 	"github.com/gorilla/websocket"
+
+	"github.com/ggresillion/breezy/watch"
 )
 
 //go:embed script.html
 var scriptHTML string
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins in development
-	},
+// buildUpgrader returns a websocket.Upgrader restricted to the given
+// allowed origins ("*" allows any). With no allowed origins configured,
+// CheckOrigin is left unset so gorilla/websocket falls back to its
+// default same-origin check instead of allowing every origin.
+func buildUpgrader(allowedOrigins []string) websocket.Upgrader {
+	if len(allowedOrigins) == 0 {
+		return websocket.Upgrader{}
+	}
+
+	return websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			for _, allowed := range allowedOrigins {
+				if allowed == "*" || allowed == origin {
+					return true
+				}
+			}
+			return false
+		},
+	}
 }
 
 // Generate a unique server ID when the server starts
 var serverStartTime = time.Now().Unix()
 
+// writerMode is the strategy responseWriter uses for a given response,
+// decided once the handler's status code and headers are known.
+type writerMode int
+
+const (
+	// modePassthrough forwards bytes to the client untouched: non-2xx
+	// statuses, non-HTML content types, and text/event-stream.
+	modePassthrough writerMode = iota
+	// modeStream splices scriptHTML into an HTML body as it's written,
+	// without buffering the whole response.
+	modeStream
+	// modeBuffer accumulates the whole body before deciding whether to
+	// inject: used when the Content-Type is unset (so the body itself
+	// must be sniffed) or the body is compressed (so it must be decoded
+	// as a whole before scanning for HTML tags).
+	modeBuffer
+)
+
+// responseWriter wraps the real http.ResponseWriter to inject the
+// live-reload script into HTML responses. It streams straight through
+// for passthrough and stream modes and only buffers when the response
+// must be sniffed or decoded first.
 type responseWriter struct {
 	http.ResponseWriter
-	body       *bytes.Buffer
-	statusCode int
+	statusCode  int
+	wroteHeader bool
+	mode        writerMode
+	encoding    string
+	injector    *injectingWriter
+	buf         *bytes.Buffer
+
+	script             string
+	injectionPredicate InjectionPredicate
+	logger             *slog.Logger
 }
 
 func (rw *responseWriter) Write(b []byte) (int, error) {
-	return rw.body.Write(b)
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	switch rw.mode {
+	case modeStream:
+		return rw.injector.Write(b)
+	case modeBuffer:
+		return rw.buf.Write(b)
+	default:
+		return rw.ResponseWriter.Write(b)
+	}
 }
 
 func (rw *responseWriter) WriteHeader(statusCode int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
 	rw.statusCode = statusCode
+
+	contentType := rw.Header().Get("Content-Type")
+	encoding := rw.Header().Get("Content-Encoding")
+
+	eligible := rw.injectionPredicate(&http.Response{StatusCode: statusCode, Header: rw.Header()})
+
+	switch {
+	case !eligible:
+		rw.mode = modePassthrough
+	case encoding != "" && encoding != "identity":
+		// Must be checked before the empty-Content-Type case below: a
+		// response can be compressed without declaring a Content-Type,
+		// and rw.encoding has to be set either way so finish() knows to
+		// decode before sniffing.
+		rw.mode = modeBuffer
+		rw.encoding = encoding
+	case contentType == "":
+		rw.mode = modeBuffer
+	default:
+		rw.mode = modeStream
+	}
+
+	switch rw.mode {
+	case modeStream:
+		rw.Header().Del("Content-Length")
+		rw.ResponseWriter.WriteHeader(statusCode)
+		rw.injector = &injectingWriter{w: flushWriter{rw.ResponseWriter}, script: rw.script}
+	case modePassthrough:
+		rw.ResponseWriter.WriteHeader(statusCode)
+	case modeBuffer:
+		// Status and headers are deferred until finish, once the full
+		// (possibly re-encoded) body and its length are known.
+		rw.buf = &bytes.Buffer{}
+	}
 }
 
-func (rw *responseWriter) Header() http.Header {
-	return rw.ResponseWriter.Header()
+// finish flushes whatever responseWriter held back: the tail of a
+// streamed injection, or a fully buffered body that still needs
+// decoding, sniffing, injecting, and re-encoding.
+func (rw *responseWriter) finish() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	switch rw.mode {
+	case modeStream:
+		if err := rw.injector.Close(); err != nil {
+			rw.logger.Error("breezy: failed to flush injected response", "error", err)
+		}
+		return
+	case modePassthrough:
+		return
+	}
+
+	body := rw.buf.Bytes()
+
+	decodedBody, known, err := decodeBody(rw.encoding, body)
+	if err != nil {
+		rw.logger.Error("breezy: failed to decode response body, skipping injection", "encoding", rw.encoding, "error", err)
+		known = false
+	}
+
+	if !known || !isHTMLResponse(string(decodedBody), rw.Header()) {
+		writeResponse(rw.ResponseWriter, rw.statusCode, body)
+		return
+	}
+
+	modifiedBody := injectScript(string(decodedBody), rw.script)
+
+	reencoded, err := encodeBody(rw.encoding, []byte(modifiedBody))
+	if err != nil {
+		rw.logger.Error("breezy: failed to re-encode response body, emitting plaintext", "encoding", rw.encoding, "error", err)
+		rw.Header().Del("Content-Encoding")
+		rw.Header().Del("Content-Length")
+		writeResponse(rw.ResponseWriter, rw.statusCode, []byte(modifiedBody))
+		return
+	}
+
+	rw.Header().Set("Content-Length", fmt.Sprint(len(reencoded)))
+	writeResponse(rw.ResponseWriter, rw.statusCode, reencoded)
 }
 
 // isHTMLResponse checks if the response looks like HTML.
@@ -65,86 +209,120 @@ func isDevelopmentMode(r *http.Request) bool {
 		strings.HasPrefix(host, "127.0.0.1:")
 }
 
-func injectScript(body string) string {
+func injectScript(body, script string) string {
 	if strings.Contains(body, "</head>") {
-		return strings.Replace(body, "</head>", scriptHTML+"\n</head>", 1)
+		return strings.Replace(body, "</head>", script+"\n</head>", 1)
 	}
 	if strings.Contains(body, "</body>") {
-		return strings.Replace(body, "</body>", scriptHTML+"\n</body>", 1)
+		return strings.Replace(body, "</body>", script+"\n</body>", 1)
 	}
 	if strings.Contains(body, "</html>") {
-		return strings.Replace(body, "</html>", scriptHTML+"\n</html>", 1)
+		return strings.Replace(body, "</html>", script+"\n</html>", 1)
 	}
-	return body + scriptHTML
+	return body + script
 }
 
 // handleLiveReload handles the WebSocket connection for live reload.
-func handleLiveReload(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		slog.Error("WebSocket upgrade failed", "error", err)
-		return
+func handleLiveReload(hub *Hub, upgrader websocket.Upgrader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			hub.logger.Error("WebSocket upgrade failed", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		hub.register <- conn
+		defer func() { hub.unregister <- conn }()
+
+		err = conn.WriteJSON(map[string]any{
+			"type":      "server-info",
+			"startTime": serverStartTime,
+		})
+		if err != nil {
+			hub.logger.Error("Failed to send server info", "error", err)
+			return
+		}
+
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				break
+			}
+		}
 	}
-	defer conn.Close()
+}
 
-	err = conn.WriteJSON(map[string]any{
-		"type":      "server-info",
-		"startTime": serverStartTime,
-	})
+// startWatcher wires the watch subsystem into hub, broadcasting a
+// "reload" frame for every coalesced filesystem change. It runs until
+// ctx is cancelled.
+func startWatcher(ctx context.Context, cfg *config, hub *Hub) {
+	w, err := watch.New(cfg.watchPaths, func(e watch.Event) {
+		hub.Broadcast(map[string]any{
+			"type": "reload",
+			"kind": e.Kind,
+			"path": e.Path,
+		})
+	}, watch.WithDebounce(cfg.watchDebounce), watch.WithLogger(cfg.logger))
 	if err != nil {
-		slog.Error("Failed to send server info", "error", err)
+		cfg.logger.Error("breezy: failed to start watcher", "error", err)
 		return
 	}
 
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			break
-		}
+	if err := w.Start(ctx); err != nil && ctx.Err() == nil {
+		cfg.logger.Error("breezy: watcher stopped unexpectedly", "error", err)
+	}
+}
+
+// writeResponse writes statusCode and body to w. Headers are expected to
+// already be set on w.Header() by the caller, since it's the same
+// header map the underlying ResponseWriter will emit.
+func writeResponse(w http.ResponseWriter, statusCode int, body []byte) {
+	if statusCode != http.StatusOK {
+		w.WriteHeader(statusCode)
 	}
+	w.Write(body)
 }
 
-// Middleware wraps an http.Handler with live reload functionality
-func Middleware(next http.Handler) http.Handler {
+// Middleware wraps an http.Handler with live reload functionality.
+func Middleware(next http.Handler, opts ...Option) http.Handler {
+	cfg := newConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.finalize()
+
+	hub := newHub(cfg.logger)
+	stop := make(chan struct{})
+	go hub.run(stop)
+
+	if len(cfg.watchPaths) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go startWatcher(ctx, cfg, hub)
+		go func() {
+			<-stop
+			cancel()
+		}()
+	}
+
+	upgrader := buildUpgrader(cfg.allowedOrigins)
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/ws/livereload", handleLiveReload)
+	mux.HandleFunc(cfg.wsPath, handleLiveReload(hub, upgrader))
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if !isDevelopmentMode(r) {
+		if !cfg.devModePredicate(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		wrapper := &responseWriter{
-			ResponseWriter: w,
-			body:           &bytes.Buffer{},
-			statusCode:     http.StatusOK,
+			ResponseWriter:     w,
+			script:             cfg.script,
+			injectionPredicate: cfg.injectionPredicate,
+			logger:             cfg.logger,
 		}
-
 		next.ServeHTTP(wrapper, r)
-
-		body := wrapper.body.String()
-
-		if !isHTMLResponse(body, wrapper.Header()) {
-			if wrapper.statusCode != http.StatusOK {
-				w.WriteHeader(wrapper.statusCode)
-			}
-			w.Write(wrapper.body.Bytes())
-			return
-		}
-
-		modifiedBody := injectScript(body)
-
-		if wrapper.statusCode != http.StatusOK {
-			w.WriteHeader(wrapper.statusCode)
-		}
-
-		for key, values := range wrapper.Header() {
-			for _, value := range values {
-				w.Header().Add(key, value)
-			}
-		}
-
-		w.Write([]byte(modifiedBody))
+		wrapper.finish()
 	})
 
 	return mux
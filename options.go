@@ -0,0 +1,127 @@
+package breezy
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultWebSocketPath = "/ws/livereload"
+
+// InjectionPredicate decides whether a response is eligible for script
+// injection. It's evaluated against the response's status and headers
+// before the body is available.
+type InjectionPredicate func(*http.Response) bool
+
+// defaultInjectionPredicate injects into any 2xx response that isn't an
+// event stream and either declares itself as text/html or doesn't
+// declare a Content-Type at all (in which case the body is sniffed).
+func defaultInjectionPredicate(resp *http.Response) bool {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if strings.Contains(contentType, "text/event-stream") {
+		return false
+	}
+	return contentType == "" || strings.Contains(contentType, "text/html")
+}
+
+// config holds the options accumulated by Option values passed to
+// Middleware and Proxy.
+type config struct {
+	watchPaths    []string
+	watchDebounce time.Duration
+
+	wsPath             string
+	allowedOrigins     []string
+	devModePredicate   func(*http.Request) bool
+	injectionPredicate InjectionPredicate
+	logger             *slog.Logger
+
+	script string
+}
+
+func newConfig() *config {
+	return &config{
+		watchDebounce:      150 * time.Millisecond,
+		wsPath:             defaultWebSocketPath,
+		devModePredicate:   isDevelopmentMode,
+		injectionPredicate: defaultInjectionPredicate,
+		logger:             slog.Default(),
+		script:             scriptHTML,
+	}
+}
+
+// finalize applies cross-cutting adjustments that depend on more than
+// one option, once every Option has run: the embedded/custom script
+// needs to know the configured websocket path.
+func (c *config) finalize() {
+	if c.wsPath != defaultWebSocketPath {
+		c.script = strings.ReplaceAll(c.script, defaultWebSocketPath, c.wsPath)
+	}
+}
+
+// Option configures the behavior of Middleware and Proxy.
+type Option func(*config)
+
+// WithWatch enables the file-watcher subsystem: the given roots are
+// watched recursively and changes are broadcast to every connected
+// live-reload client.
+func WithWatch(paths ...string) Option {
+	return func(c *config) {
+		c.watchPaths = append(c.watchPaths, paths...)
+	}
+}
+
+// WithWebSocketPath overrides the path the live-reload websocket is
+// served on. The default is "/ws/livereload".
+func WithWebSocketPath(path string) Option {
+	return func(c *config) { c.wsPath = path }
+}
+
+// WithAllowedOrigins restricts the live-reload websocket upgrade to the
+// given Origin values ("*" allows any origin). Without this option the
+// upgrader falls back to gorilla/websocket's same-origin check instead
+// of allowing every origin.
+func WithAllowedOrigins(origins []string) Option {
+	return func(c *config) { c.allowedOrigins = origins }
+}
+
+// WithDevModePredicate overrides how Middleware decides whether a
+// request is in "development mode" (and therefore eligible for script
+// injection). The default matches localhost and 127.0.0.1 hosts; pass a
+// custom predicate to also allow .local hostnames, Tailscale names, or
+// Docker bridge IPs.
+func WithDevModePredicate(predicate func(*http.Request) bool) Option {
+	return func(c *config) { c.devModePredicate = predicate }
+}
+
+// WithInjectionPredicate overrides how Middleware and Proxy decide
+// whether a given response is eligible for script injection. The
+// default injects into 2xx, non-event-stream responses that are HTML or
+// declare no Content-Type at all.
+func WithInjectionPredicate(predicate InjectionPredicate) Option {
+	return func(c *config) { c.injectionPredicate = predicate }
+}
+
+// WithCustomScript replaces the embedded live-reload client script with
+// the contents of r.
+func WithCustomScript(r io.Reader) Option {
+	return func(c *config) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			c.logger.Error("breezy: failed to read custom script, keeping the default", "error", err)
+			return
+		}
+		c.script = string(b)
+	}
+}
+
+// WithLogger sets the logger used for non-fatal breezy errors, such as
+// a watcher or websocket failure. The default is slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) { c.logger = logger }
+}
@@ -0,0 +1,122 @@
+package breezy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuildUpgraderDefaultsToSameOrigin(t *testing.T) {
+	upgrader := buildUpgrader(nil)
+	if upgrader.CheckOrigin != nil {
+		t.Fatal("expected CheckOrigin to be unset without allowed origins, got a blanket-allow func")
+	}
+}
+
+func TestBuildUpgraderAllowsConfiguredOrigins(t *testing.T) {
+	upgrader := buildUpgrader([]string{"https://example.com"})
+
+	allowed := httptest.NewRequest(http.MethodGet, "/ws/livereload", nil)
+	allowed.Header.Set("Origin", "https://example.com")
+	if !upgrader.CheckOrigin(allowed) {
+		t.Fatal("expected configured origin to be allowed")
+	}
+
+	denied := httptest.NewRequest(http.MethodGet, "/ws/livereload", nil)
+	denied.Header.Set("Origin", "https://evil.example")
+	if upgrader.CheckOrigin(denied) {
+		t.Fatal("expected unconfigured origin to be denied")
+	}
+}
+
+func TestBuildUpgraderWildcardAllowsAnyOrigin(t *testing.T) {
+	upgrader := buildUpgrader([]string{"*"})
+
+	req := httptest.NewRequest(http.MethodGet, "/ws/livereload", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	if !upgrader.CheckOrigin(req) {
+		t.Fatal("expected \"*\" to allow any origin")
+	}
+}
+
+func TestWithWebSocketPathRewritesServedRouteAndScript(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head></head><body>hi</body></html>"))
+	})
+
+	mux := Middleware(handler, WithWebSocketPath("/custom/livereload"))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "/custom/livereload") {
+		t.Fatalf("expected injected script to reference the custom websocket path, got: %s", rec.Body.String())
+	}
+
+	wsReq := httptest.NewRequest(http.MethodGet, "http://localhost/custom/livereload", nil)
+	wsRec := httptest.NewRecorder()
+	mux.ServeHTTP(wsRec, wsReq)
+
+	if wsRec.Code == http.StatusOK {
+		t.Fatalf("expected /custom/livereload to reach the websocket handler and fail the plain HTTP upgrade, got 200")
+	}
+}
+
+func TestWithCustomScriptReplacesEmbeddedScript(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head></head><body>hi</body></html>"))
+	})
+
+	mux := Middleware(handler, WithCustomScript(strings.NewReader("<script>custom</script>")))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "<script>custom</script>") {
+		t.Fatalf("expected custom script to be injected, got: %s", rec.Body.String())
+	}
+}
+
+func TestWithInjectionPredicateOverridesDefault(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	mux := Middleware(handler, WithInjectionPredicate(func(*http.Response) bool { return true }))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "<script>") {
+		t.Fatalf("expected script injected into a non-HTML response when the predicate allows it, got: %s", rec.Body.String())
+	}
+}
+
+func TestWithDevModePredicateOverridesDefault(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head></head><body>hi</body></html>"))
+	})
+
+	mux := Middleware(handler, WithDevModePredicate(func(*http.Request) bool { return false }))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if !called {
+		t.Fatal("expected the wrapped handler to run")
+	}
+	if strings.Contains(rec.Body.String(), "<script>") {
+		t.Fatalf("expected no script injection once dev mode is disabled, got: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package breezy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// Proxy returns an http.Handler that reverse-proxies every request to
+// target, except the live-reload websocket path, which it serves itself,
+// injecting the live-reload script into proxied HTML responses.
+// It lets you run breezy in front of an existing backend (Go, Node,
+// Python, ...) without touching its code:
+//
+//	http.ListenAndServe(":8080", breezy.Proxy(target, breezy.WithWatch("./templates")))
+func Proxy(target *url.URL, opts ...Option) http.Handler {
+	cfg := newConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.finalize()
+
+	hub := newHub(cfg.logger)
+	stop := make(chan struct{})
+	go hub.run(stop)
+
+	if len(cfg.watchPaths) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go startWatcher(ctx, cfg, hub)
+		go func() {
+			<-stop
+			cancel()
+		}()
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	originalDirector := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		originalHost := r.Host
+		originalProto := "http"
+		if r.TLS != nil {
+			originalProto = "https"
+		}
+
+		originalDirector(r)
+
+		r.Host = target.Host
+		r.Header.Set("X-Forwarded-Host", originalHost)
+		r.Header.Set("X-Forwarded-Proto", originalProto)
+	}
+
+	proxy.ModifyResponse = injectIntoProxyResponse(cfg)
+
+	upgrader := buildUpgrader(cfg.allowedOrigins)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(cfg.wsPath, handleLiveReload(hub, upgrader))
+	mux.Handle("/", proxy)
+
+	return mux
+}
+
+// injectIntoProxyResponse returns the ReverseProxy ModifyResponse hook
+// that performs the same HTML sniffing and script injection the
+// middleware path does, leaving ineligible responses untouched.
+func injectIntoProxyResponse(cfg *config) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		if !cfg.injectionPredicate(resp) {
+			return nil
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("breezy: reading proxied response body: %w", err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		encoding := resp.Header.Get("Content-Encoding")
+		decodedBody, known, err := decodeBody(encoding, body)
+		if err != nil {
+			cfg.logger.Error("breezy: failed to decode proxied response body, skipping injection", "encoding", encoding, "error", err)
+			return nil
+		}
+		if !known || !isHTMLResponse(string(decodedBody), resp.Header) {
+			return nil
+		}
+
+		modifiedBody := injectScript(string(decodedBody), cfg.script)
+
+		reencoded, err := encodeBody(encoding, []byte(modifiedBody))
+		if err != nil {
+			cfg.logger.Error("breezy: failed to re-encode proxied response body, emitting plaintext", "encoding", encoding, "error", err)
+			resp.Header.Del("Content-Encoding")
+			resp.Body = io.NopCloser(strings.NewReader(modifiedBody))
+			resp.ContentLength = int64(len(modifiedBody))
+			resp.Header.Set("Content-Length", fmt.Sprint(len(modifiedBody)))
+			return nil
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(reencoded))
+		resp.ContentLength = int64(len(reencoded))
+		resp.Header.Set("Content-Length", fmt.Sprint(len(reencoded)))
+		return nil
+	}
+}
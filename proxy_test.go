@@ -0,0 +1,69 @@
+package breezy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProxyInjectsScriptAndForwardsHeaders(t *testing.T) {
+	var gotHost, gotForwardedHost string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		gotForwardedHost = r.Header.Get("X-Forwarded-Host")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head></head><body>upstream</body></html>"))
+	}))
+	defer upstream.Close()
+
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+
+	proxy := Proxy(target)
+
+	req := httptest.NewRequest(http.MethodGet, "http://dev.local/", nil)
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+
+	body, _ := io.ReadAll(rec.Result().Body)
+	if !strings.Contains(string(body), "<script>") {
+		t.Fatalf("expected injected script, got: %s", body)
+	}
+	if !strings.Contains(string(body), "upstream") {
+		t.Fatalf("expected upstream content preserved, got: %s", body)
+	}
+
+	if gotHost != target.Host {
+		t.Fatalf("upstream saw Host %q, want %q", gotHost, target.Host)
+	}
+	if gotForwardedHost != "dev.local" {
+		t.Fatalf("X-Forwarded-Host = %q, want dev.local", gotForwardedHost)
+	}
+
+	if cl := rec.Result().Header.Get("Content-Length"); cl == "" {
+		t.Fatal("expected Content-Length to be recomputed after injection")
+	}
+}
+
+func TestProxyInterceptsLiveReloadRoute(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("upstream should not receive /ws/livereload requests, got %s", r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	proxy := Proxy(target)
+
+	req := httptest.NewRequest(http.MethodGet, "http://dev.local/ws/livereload", nil)
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+
+	if rec.Code == http.StatusOK {
+		t.Fatalf("expected the websocket upgrade to fail for a plain HTTP request, got 200")
+	}
+}
@@ -0,0 +1,129 @@
+package breezy
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/ggresillion/breezy/watch"
+)
+
+// defaultErrorPattern matches the file:line:col: message format used by
+// `go build` and most other Go tooling.
+var defaultErrorPattern = regexp.MustCompile(`(?m)^(\S+\.go):(\d+):(\d+):\s*(.*)$`)
+
+// runConfig holds the options accumulated by RunOption values passed to
+// Run.
+type runConfig struct {
+	watchPaths    []string
+	watchDebounce time.Duration
+	errorPattern  *regexp.Regexp
+	target        *url.URL
+	logger        *slog.Logger
+}
+
+func newRunConfig() *runConfig {
+	target, _ := url.Parse("http://localhost:8080")
+	return &runConfig{
+		watchDebounce: 150 * time.Millisecond,
+		errorPattern:  defaultErrorPattern,
+		target:        target,
+		logger:        slog.Default(),
+	}
+}
+
+// RunOption configures the behavior of Run.
+type RunOption func(*runConfig)
+
+// WithRunWatch enables the file-watcher subsystem for Run: the given
+// roots are watched recursively and trigger a supervised restart of the
+// child process.
+func WithRunWatch(paths ...string) RunOption {
+	return func(c *runConfig) {
+		c.watchPaths = append(c.watchPaths, paths...)
+	}
+}
+
+// WithErrorPattern overrides the regular expression used to recognize
+// compiler/build error lines in the child process's output. Matches
+// must have four submatches: file, line, column, message.
+func WithErrorPattern(pattern *regexp.Regexp) RunOption {
+	return func(c *runConfig) { c.errorPattern = pattern }
+}
+
+// WithTarget sets the address the supervised child process listens on.
+// Requests are reverse-proxied there, the same way Proxy works. The
+// default is http://localhost:8080.
+func WithTarget(target *url.URL) RunOption {
+	return func(c *runConfig) { c.target = target }
+}
+
+// WithRunLogger sets the logger used for non-fatal breezy errors raised
+// by Run's supervisor, watcher, and proxy. The default is
+// slog.Default().
+func WithRunLogger(logger *slog.Logger) RunOption {
+	return func(c *runConfig) { c.logger = logger }
+}
+
+// Run starts cmd as a supervised child process, restarting it whenever
+// a watched file changes, and reverse-proxies requests to it. Build or
+// runtime errors detected in the child's output are pushed to connected
+// clients as a build-error overlay. The overlay is cleared whenever the
+// child is restarted, before the new run's outcome is known; if that run
+// fails too, a new overlay is shown.
+func Run(cmd string, args []string, opts ...RunOption) http.Handler {
+	cfg := newRunConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	handlerCfg := newConfig()
+	handlerCfg.logger = cfg.logger
+
+	hub := newHub(handlerCfg.logger)
+	stop := make(chan struct{})
+	go hub.run(stop)
+
+	sup := newSupervisor(cmd, args, cfg.errorPattern, hub, cfg.logger)
+	go sup.start()
+
+	if len(cfg.watchPaths) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go runWatcher(ctx, cfg, sup)
+		go func() {
+			<-stop
+			cancel()
+		}()
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(cfg.target)
+	proxy.ModifyResponse = injectIntoProxyResponse(handlerCfg)
+
+	upgrader := buildUpgrader(handlerCfg.allowedOrigins)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(handlerCfg.wsPath, handleLiveReload(hub, upgrader))
+	mux.Handle("/", proxy)
+
+	return mux
+}
+
+// runWatcher restarts sup's child process whenever a watched file
+// changes. It runs until ctx is cancelled.
+func runWatcher(ctx context.Context, cfg *runConfig, sup *supervisor) {
+	w, err := watch.New(cfg.watchPaths, func(watch.Event) {
+		sup.restart()
+	}, watch.WithDebounce(cfg.watchDebounce), watch.WithLogger(cfg.logger))
+	if err != nil {
+		cfg.logger.Error("breezy: failed to start watcher", "error", err)
+		return
+	}
+
+	if err := w.Start(ctx); err != nil && ctx.Err() == nil {
+		cfg.logger.Error("breezy: watcher stopped unexpectedly", "error", err)
+	}
+}
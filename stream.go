@@ -0,0 +1,149 @@
+package breezy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// closingTags are the HTML tags injectingWriter watches for, in the
+// order injectScript prefers them.
+var closingTags = [][]byte{
+	[]byte("</head>"),
+	[]byte("</body>"),
+	[]byte("</html>"),
+}
+
+// maxTagLen is the length of the longest closing tag we scan for. One
+// less than it is the number of bytes we must carry over a write
+// boundary in case a tag is split across two Write calls.
+var maxTagLen = len("</html>")
+
+// injectingWriter splices iw.script into an HTML stream the first time
+// a closing tag is seen, without ever buffering the whole body. Writes
+// after the splice point are passed through untouched.
+type injectingWriter struct {
+	w        writeFlusher
+	script   string
+	carry    []byte
+	injected bool
+}
+
+type writeFlusher interface {
+	Write([]byte) (int, error)
+	Flush()
+}
+
+func (iw *injectingWriter) Write(p []byte) (int, error) {
+	if iw.injected {
+		return iw.w.Write(p)
+	}
+
+	data := p
+	if len(iw.carry) > 0 {
+		data = append(iw.carry, p...)
+		iw.carry = nil
+	}
+
+	if idx, tagLen := earliestClosingTag(data); idx >= 0 {
+		if _, err := iw.w.Write(data[:idx+tagLen]); err != nil {
+			return 0, err
+		}
+		if _, err := iw.w.Write([]byte(iw.script)); err != nil {
+			return 0, err
+		}
+		if _, err := iw.w.Write(data[idx+tagLen:]); err != nil {
+			return 0, err
+		}
+		iw.injected = true
+		return len(p), nil
+	}
+
+	keep := len(data)
+	if keep > maxTagLen-1 {
+		flush := keep - (maxTagLen - 1)
+		if _, err := iw.w.Write(data[:flush]); err != nil {
+			return 0, err
+		}
+		iw.carry = append([]byte(nil), data[flush:]...)
+	} else {
+		iw.carry = data
+	}
+
+	return len(p), nil
+}
+
+// Close flushes any carried bytes. If no closing tag was ever seen, the
+// script is appended at the very end, mirroring injectScript's fallback
+// for bodies with no head/body/html tag.
+func (iw *injectingWriter) Close() error {
+	if len(iw.carry) > 0 {
+		if _, err := iw.w.Write(iw.carry); err != nil {
+			return err
+		}
+		iw.carry = nil
+	}
+	if !iw.injected {
+		if _, err := iw.w.Write([]byte(iw.script)); err != nil {
+			return err
+		}
+		iw.injected = true
+	}
+	return nil
+}
+
+// earliestClosingTag returns the index and length of whichever closing
+// tag occurs first in data, or (-1, 0) if none is present.
+func earliestClosingTag(data []byte) (idx, tagLen int) {
+	idx = -1
+	for _, tag := range closingTags {
+		if i := bytes.Index(data, tag); i >= 0 && (idx == -1 || i < idx) {
+			idx, tagLen = i, len(tag)
+		}
+	}
+	return idx, tagLen
+}
+
+// flushWriter adapts an http.ResponseWriter to writeFlusher, flushing
+// after every write when the underlying writer supports it so streamed
+// chunks reach the client immediately.
+type flushWriter struct {
+	http.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.ResponseWriter.Write(p)
+	if f, ok := fw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+func (fw flushWriter) Flush() {
+	if f, ok := fw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Flush implements http.Flusher so downstream handlers streaming SSE or
+// chunked HTML through the wrapper keep working.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker so a websocket upgrade performed by a
+// downstream handler still works when wrapped by Middleware.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("breezy: underlying ResponseWriter does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+var _ http.Flusher = (*responseWriter)(nil)
+var _ http.Hijacker = (*responseWriter)(nil)
@@ -0,0 +1,124 @@
+package breezy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type discardFlusher struct {
+	io.Writer
+}
+
+func (discardFlusher) Flush() {}
+
+func TestInjectingWriterSplicesWithinOneWrite(t *testing.T) {
+	var out bytes.Buffer
+	iw := &injectingWriter{w: discardFlusher{&out}, script: scriptHTML}
+
+	iw.Write([]byte("<html><head></head><body>hi</body></html>"))
+	if err := iw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "<script>") {
+		t.Fatalf("expected injected script, got: %s", out.String())
+	}
+	if !strings.HasPrefix(out.String(), "<html><head></head>") {
+		t.Fatalf("expected script spliced right after </head>, got: %s", out.String())
+	}
+}
+
+func TestInjectingWriterSplicesAcrossWriteBoundary(t *testing.T) {
+	var out bytes.Buffer
+	iw := &injectingWriter{w: discardFlusher{&out}, script: scriptHTML}
+
+	body := "<html><head></head><body>hi</body></html>"
+	for i := 0; i < len(body); i++ {
+		iw.Write([]byte{body[i]})
+	}
+	if err := iw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "<script>") {
+		t.Fatalf("expected injected script even when tags split across writes, got: %s", out.String())
+	}
+}
+
+func TestInjectingWriterFallsBackWhenNoTagFound(t *testing.T) {
+	var out bytes.Buffer
+	iw := &injectingWriter{w: discardFlusher{&out}, script: scriptHTML}
+
+	iw.Write([]byte("plain text with no html tags"))
+	if err := iw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if !strings.HasSuffix(out.String(), scriptHTML) {
+		t.Fatalf("expected script appended at the end, got: %s", out.String())
+	}
+}
+
+func TestMiddlewareStreamsChunkedHTMLWithoutBuffering(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<html><head></head><body>"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("hi</body></html>"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	Middleware(handler).ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "<script>") {
+		t.Fatalf("expected injected script, got: %s", body)
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("expected no Content-Length on a streamed response, got %q", rec.Header().Get("Content-Length"))
+	}
+}
+
+func TestMiddlewarePassesThroughEventStream(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("data: hello\n\n"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	rec := httptest.NewRecorder()
+	Middleware(handler).ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); body != "data: hello\n\n" {
+		t.Fatalf("expected SSE body untouched, got: %s", body)
+	}
+}
+
+// BenchmarkInjectingWriterLargeBody exercises a multi-MB HTML stream in
+// small chunks and reports allocations per op: they should stay flat as
+// the body grows, proving we never buffer the whole response.
+func BenchmarkInjectingWriterLargeBody(b *testing.B) {
+	const chunkSize = 4096
+	chunk := bytes.Repeat([]byte("x"), chunkSize)
+	chunks := 1000 // ~4MB body
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		iw := &injectingWriter{w: discardFlusher{io.Discard}, script: scriptHTML}
+		iw.Write([]byte("<html><head></head><body>"))
+		for j := 0; j < chunks; j++ {
+			iw.Write(chunk)
+		}
+		iw.Write([]byte("</body></html>"))
+		iw.Close()
+	}
+}
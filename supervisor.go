@@ -0,0 +1,167 @@
+package breezy
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// supervisor starts and restarts a child process, scanning its output
+// for build/compile errors and reporting them to a Hub.
+type supervisor struct {
+	name string
+	args []string
+
+	errorPattern *regexp.Regexp
+	hub          *Hub
+	logger       *slog.Logger
+
+	mu      sync.Mutex
+	current *process
+}
+
+// process tracks one launch of the supervised child: the exec.Cmd, a
+// channel closed once its Wait goroutine returns, whether it was killed
+// intentionally by restart (so the kill isn't reported as a build
+// error), and whether scan already reported a parsed error line (so the
+// exit code isn't reported a second time as a less useful duplicate).
+type process struct {
+	cmd          *exec.Cmd
+	done         chan struct{}
+	stopped      atomic.Bool
+	matchedError atomic.Bool
+}
+
+func newSupervisor(name string, args []string, errorPattern *regexp.Regexp, hub *Hub, logger *slog.Logger) *supervisor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &supervisor{
+		name:         name,
+		args:         args,
+		errorPattern: errorPattern,
+		hub:          hub,
+		logger:       logger,
+	}
+}
+
+// start launches the child process for the first time.
+func (s *supervisor) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.launchLocked()
+}
+
+// restart kills the current child process, if any, and launches a new
+// one. It's safe to call concurrently and from the watcher goroutine.
+func (s *supervisor) restart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && s.current.cmd.Process != nil {
+		s.current.stopped.Store(true)
+		s.current.cmd.Process.Kill()
+		<-s.current.done
+	}
+
+	s.hub.Broadcast(map[string]any{"type": "build-clear"})
+	s.launchLocked()
+}
+
+// launchLocked starts s.current. s.mu must be held. The started
+// process's own goroutine is the sole owner of cmd.Wait, so restart
+// coordinates through proc.done rather than calling Wait itself.
+func (s *supervisor) launchLocked() {
+	cmd := exec.Command(s.name, s.args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		s.logger.Error("breezy: failed to open stdout pipe", "error", err)
+		return
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		s.logger.Error("breezy: failed to open stderr pipe", "error", err)
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		s.logger.Error("breezy: failed to start child process", "cmd", s.name, "error", err)
+		return
+	}
+
+	proc := &process{cmd: cmd, done: make(chan struct{})}
+	s.current = proc
+
+	// os/exec requires every read from StdoutPipe/StderrPipe to finish
+	// before Wait is called, or the pipes can be closed out from under
+	// scan mid-read and truncate the final output. scanDone tracks that.
+	var scanDone sync.WaitGroup
+	scanDone.Add(2)
+	go func() { defer scanDone.Done(); s.scan(stdout, "stdout", proc) }()
+	go func() { defer scanDone.Done(); s.scan(stderr, "stderr", proc) }()
+
+	go func() {
+		defer close(proc.done)
+		scanDone.Wait()
+
+		err := cmd.Wait()
+		if err == nil {
+			return
+		}
+		s.logger.Error("breezy: child process exited", "cmd", s.name, "error", err)
+
+		if proc.stopped.Load() || proc.matchedError.Load() {
+			return
+		}
+		if _, ok := err.(*exec.ExitError); ok {
+			s.hub.Broadcast(map[string]any{
+				"type":   "build-error",
+				"stderr": err.Error(),
+			})
+		}
+	}()
+}
+
+// scan reads r line by line, relaying it to the application log and
+// pushing a build-error frame to the hub for any line matching
+// s.errorPattern. It marks proc.matchedError so the exit-code handler
+// in launchLocked doesn't clobber the richer frame with a generic one.
+func (s *supervisor) scan(r io.Reader, stream string, proc *process) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		s.logger.Info("breezy: child process output", "stream", stream, "line", line)
+
+		file, lineNo, message, ok := parseErrorLine(s.errorPattern, line)
+		if !ok {
+			continue
+		}
+
+		proc.matchedError.Store(true)
+		s.hub.Broadcast(map[string]any{
+			"type":   "build-error",
+			"stderr": message,
+			"file":   file,
+			"line":   lineNo,
+		})
+	}
+}
+
+// parseErrorLine extracts the file, line number, and message from a
+// line of child-process output matching pattern. pattern must capture
+// exactly four groups: file, line, column, message.
+func parseErrorLine(pattern *regexp.Regexp, line string) (file string, lineNo int, message string, ok bool) {
+	match := pattern.FindStringSubmatch(line)
+	if match == nil {
+		return "", 0, "", false
+	}
+
+	lineNo, _ = strconv.Atoi(match[2])
+	return match[1], lineNo, match[4], true
+}
@@ -0,0 +1,118 @@
+package breezy
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestSupervisorBroadcastsBuildErrorOnNonZeroExit(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	hub := newHub(nil)
+	msgs := make(chan map[string]any, 4)
+	go func() {
+		for msg := range hub.broadcast {
+			if m, ok := msg.(map[string]any); ok {
+				msgs <- m
+			}
+		}
+	}()
+
+	sup := newSupervisor("sh", []string{"-c", "exit 1"}, defaultErrorPattern, hub, nil)
+	sup.start()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-msgs:
+			if msg["type"] == "build-error" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected a build-error broadcast for the non-zero exit")
+		}
+	}
+}
+
+func TestSupervisorSuppressesExitFrameWhenScanAlreadyMatched(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	hub := newHub(nil)
+	msgs := make(chan map[string]any, 4)
+	go func() {
+		for msg := range hub.broadcast {
+			if m, ok := msg.(map[string]any); ok {
+				msgs <- m
+			}
+		}
+	}()
+
+	sup := newSupervisor("sh", []string{"-c", "echo 'main.go:12:5: undefined: foo' 1>&2; exit 1"}, defaultErrorPattern, hub, nil)
+	sup.start()
+
+	var buildErrors []map[string]any
+	timeout := time.After(2 * time.Second)
+collect:
+	for {
+		select {
+		case msg := <-msgs:
+			if msg["type"] == "build-error" {
+				buildErrors = append(buildErrors, msg)
+			}
+		case <-time.After(500 * time.Millisecond):
+			break collect
+		case <-timeout:
+			t.Fatal("timed out waiting for the child process to exit")
+		}
+	}
+
+	if len(buildErrors) != 1 {
+		t.Fatalf("expected exactly one build-error frame, got %d: %v", len(buildErrors), buildErrors)
+	}
+	if buildErrors[0]["file"] != "main.go" {
+		t.Fatalf("expected the rich parsed frame, got a generic exit-code frame: %v", buildErrors[0])
+	}
+}
+
+func TestSupervisorRestartDoesNotDoubleWait(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	hub := newHub(nil)
+	go func() {
+		for range hub.broadcast {
+		}
+	}()
+
+	sup := newSupervisor("sleep", []string{"5"}, defaultErrorPattern, hub, nil)
+	sup.start()
+	time.Sleep(50 * time.Millisecond)
+
+	// Restarting twice in a row exercises the kill+reap coordination; it
+	// must not hang or race with launchLocked's own cmd.Wait goroutine.
+	sup.restart()
+	sup.restart()
+}
+
+func TestParseErrorLine(t *testing.T) {
+	file, line, message, ok := parseErrorLine(defaultErrorPattern, "main.go:12:5: undefined: foo")
+	if !ok {
+		t.Fatal("expected line to match the default error pattern")
+	}
+	if file != "main.go" || line != 12 || message != "undefined: foo" {
+		t.Fatalf("got (%q, %d, %q), want (main.go, 12, undefined: foo)", file, line, message)
+	}
+}
+
+func TestParseErrorLineIgnoresNonMatchingOutput(t *testing.T) {
+	_, _, _, ok := parseErrorLine(defaultErrorPattern, "listening on :8080")
+	if ok {
+		t.Fatal("expected non-error output not to match")
+	}
+}
@@ -0,0 +1,210 @@
+// Package watch recursively watches filesystem roots for changes and
+// reports coalesced, classified change events.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Event describes a single coalesced filesystem change.
+type Event struct {
+	// Kind is the classification of the change, e.g. "css-update" or
+	// "full-reload".
+	Kind string
+	// Path is the file that triggered the event. Empty for KindFullReload
+	// events, which stand for the whole burst of pending changes even
+	// when only one file changed.
+	Path string
+}
+
+const (
+	KindCSSUpdate  = "css-update"
+	KindFullReload = "full-reload"
+)
+
+// defaultExtensions maps file extensions to the event kind they should
+// produce. Extensions not present here fall back to KindFullReload.
+var defaultExtensions = map[string]string{
+	".css": KindCSSUpdate,
+}
+
+// Option configures a Watcher.
+type Option func(*Watcher)
+
+// WithDebounce sets the window used to coalesce bursts of filesystem
+// events into a single Event. The default is 150ms.
+func WithDebounce(d time.Duration) Option {
+	return func(w *Watcher) { w.debounce = d }
+}
+
+// WithExtensions overrides the extension-to-kind classification table.
+// Extensions not present in the map are classified as KindFullReload.
+func WithExtensions(extensions map[string]string) Option {
+	return func(w *Watcher) { w.extensions = extensions }
+}
+
+// WithLogger sets the logger used to report non-fatal watch errors.
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *Watcher) { w.logger = logger }
+}
+
+// Watcher recursively watches a set of root directories and invokes a
+// callback with coalesced, classified change events.
+type Watcher struct {
+	roots      []string
+	debounce   time.Duration
+	extensions map[string]string
+	logger     *slog.Logger
+	onEvent    func(Event)
+
+	fsw *fsnotify.Watcher
+
+	mu      sync.Mutex
+	pending map[string]Event
+	timer   *time.Timer
+}
+
+// New creates a Watcher over the given root directories. onEvent is
+// called from a timer goroutine once per coalesced change, concurrently
+// with Start's caller. A call that blocks does not stall the watch loop,
+// but a later burst can then be delivered while it is still running.
+func New(roots []string, onEvent func(Event), opts ...Option) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("watch: creating fsnotify watcher: %w", err)
+	}
+
+	w := &Watcher{
+		roots:      roots,
+		debounce:   150 * time.Millisecond,
+		extensions: defaultExtensions,
+		logger:     slog.Default(),
+		onEvent:    onEvent,
+		fsw:        fsw,
+		pending:    make(map[string]Event),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	for _, root := range roots {
+		if err := w.addRecursive(root); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (w *Watcher) addRecursive(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := w.fsw.Add(path); err != nil {
+				return fmt.Errorf("watch: adding %s: %w", path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Start runs the watch loop until ctx is cancelled or Close is called.
+func (w *Watcher) Start(ctx context.Context) error {
+	defer w.fsw.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.handleFSEvent(event)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Error("watch: fsnotify error", "error", err)
+		}
+	}
+}
+
+// Close stops the watcher and releases the underlying OS resources.
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
+
+func (w *Watcher) handleFSEvent(event fsnotify.Event) {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+		return
+	}
+
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		if event.Op&fsnotify.Create != 0 {
+			if err := w.addRecursive(event.Name); err != nil {
+				w.logger.Error("watch: failed to watch new directory", "path", event.Name, "error", err)
+			}
+		}
+		return
+	}
+
+	kind := w.classify(event.Name)
+
+	w.mu.Lock()
+	w.pending[event.Name] = Event{Kind: kind, Path: event.Name}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.debounce, w.flush)
+	} else {
+		w.timer.Reset(w.debounce)
+	}
+	w.mu.Unlock()
+}
+
+func (w *Watcher) classify(path string) string {
+	kind, ok := w.extensions[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return KindFullReload
+	}
+	return kind
+}
+
+// flush emits the pending events, merging them into a single full-reload
+// event if any of them require one.
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[string]Event)
+	w.timer = nil
+	w.mu.Unlock()
+
+	fullReload := false
+	for _, e := range pending {
+		if e.Kind == KindFullReload {
+			fullReload = true
+			break
+		}
+	}
+
+	if fullReload {
+		w.onEvent(Event{Kind: KindFullReload})
+		return
+	}
+
+	for _, e := range pending {
+		w.onEvent(e)
+	}
+}
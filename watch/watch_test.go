@@ -0,0 +1,82 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherClassifiesAndDebounces(t *testing.T) {
+	dir := t.TempDir()
+
+	events := make(chan Event, 10)
+	w, err := New([]string{dir}, func(e Event) { events <- e }, WithDebounce(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Start(ctx)
+
+	cssPath := filepath.Join(dir, "style.css")
+	if err := os.WriteFile(cssPath, []byte("body {}"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Kind != KindCSSUpdate {
+			t.Fatalf("got kind %q, want %q", e.Kind, KindCSSUpdate)
+		}
+		if e.Path != cssPath {
+			t.Fatalf("got path %q, want %q", e.Path, cssPath)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for css-update event")
+	}
+
+	select {
+	case e := <-events:
+		t.Fatalf("expected the file's create and write to be debounced into one event, got extra event %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatcherMergesBurstsIntoFullReload(t *testing.T) {
+	dir := t.TempDir()
+
+	events := make(chan Event, 10)
+	w, err := New([]string{dir}, func(e Event) { events <- e }, WithDebounce(30*time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Start(ctx)
+
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte("body {}"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Kind != KindFullReload {
+			t.Fatalf("got kind %q, want %q", e.Kind, KindFullReload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for full-reload event")
+	}
+
+	select {
+	case e := <-events:
+		t.Fatalf("expected events to be merged, got extra event %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}